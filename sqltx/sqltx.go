@@ -0,0 +1,66 @@
+// Package sqltx adapts tx-keeper to database/sql, for the common case of
+// updating a database and calling out to something else (an external API, a
+// queue, a cache) that should be undone if either side fails.
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Pizhlo/tx-keeper/transaction"
+)
+
+// Run begins a real *sql.Tx and an in-memory tx-keeper Transaction, and
+// passes both to fn. fn should use tx for SQL statements and register any
+// non-SQL side effects on k (e.g. via k.Step) along with their compensating
+// rollback.
+//
+// The SQL transaction is committed only if fn returns nil and every
+// operation queued on k also succeeds: k's commit gates the SQL commit, not
+// the other way around, so a Step-registered external side effect can still
+// be rolled back (via its paired rollback, run internally by doCommit) if a
+// later step fails, before the SQL side is ever touched. On any failure, or
+// if fn panics, both the SQL transaction and k's queued rollback are rolled
+// back. The one case this can't undo is k committing successfully and the
+// SQL commit failing afterwards: k is already in its committed state by
+// then, so the compensating k.DoRollback call below is a no-op for it.
+func Run(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx, k *transaction.Transaction) error) (err error) {
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("tx-keeper/sqltx: begin sql transaction: %w", err)
+	}
+
+	k := transaction.NewTransaction()
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = sqlTx.Rollback()
+			_ = k.DoRollback(ctx)
+
+			panic(r)
+		}
+	}()
+
+	if fnErr := fn(sqlTx, k); fnErr != nil {
+		_ = sqlTx.Rollback()
+		_ = k.DoRollback(ctx)
+
+		return fmt.Errorf("tx-keeper/sqltx: run: %w", fnErr)
+	}
+
+	if commitErr := k.DoCommit(ctx); commitErr != nil {
+		_ = sqlTx.Rollback()
+		_ = k.DoRollback(ctx)
+
+		return fmt.Errorf("tx-keeper/sqltx: commit queued operations: %w", commitErr)
+	}
+
+	if commitErr := sqlTx.Commit(); commitErr != nil {
+		_ = k.DoRollback(ctx)
+
+		return fmt.Errorf("tx-keeper/sqltx: commit sql transaction: %w", commitErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/Pizhlo/tx-keeper/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver is a minimal database/sql driver that supports BeginTx/Commit/
+// Rollback without talking to a real database, so Run's transaction plumbing
+// can be exercised without an external dependency.
+type fakeDriver struct {
+	failCommit bool
+}
+
+func (d fakeDriver) Open(_ string) (driver.Conn, error) {
+	return &fakeConn{failCommit: d.failCommit}, nil
+}
+
+type fakeConn struct {
+	failCommit bool
+}
+
+func (*fakeConn) Prepare(_ string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (*fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return &fakeTx{failCommit: c.failCommit}, nil }
+
+type fakeTx struct {
+	failCommit bool
+}
+
+func (tx *fakeTx) Commit() error {
+	if tx.failCommit {
+		return fmt.Errorf("sql commit failed")
+	}
+
+	return nil
+}
+
+func (*fakeTx) Rollback() error { return nil }
+
+func openFakeDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	return openFakeDBWithDriver(t, fakeDriver{})
+}
+
+func openFakeDBWithDriver(t *testing.T, driver fakeDriver) *sql.DB {
+	t.Helper()
+
+	name := t.Name()
+	sql.Register(name, driver)
+
+	db, err := sql.Open(name, "")
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestRunCommitsWhenEverythingSucceeds(t *testing.T) {
+	t.Parallel()
+
+	db := openFakeDB(t)
+
+	stepRan := false
+
+	err := Run(t.Context(), db, func(_ *sql.Tx, k *transaction.Transaction) error {
+		k.Step(
+			func(_ context.Context, _ ...any) error {
+				stepRan = true
+				return nil
+			},
+			func(_ context.Context, _ ...any) error {
+				return nil
+			},
+		)
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.True(t, stepRan)
+}
+
+func TestRunRollsBackWhenFnFails(t *testing.T) {
+	t.Parallel()
+
+	db := openFakeDB(t)
+
+	err := Run(t.Context(), db, func(_ *sql.Tx, _ *transaction.Transaction) error {
+		return fmt.Errorf("boom")
+	})
+
+	require.Error(t, err)
+}
+
+func TestRunRollsBackQueuedRollbackWhenFnFails(t *testing.T) {
+	t.Parallel()
+
+	db := openFakeDB(t)
+
+	rolledBack := false
+
+	err := Run(t.Context(), db, func(_ *sql.Tx, k *transaction.Transaction) error {
+		k.AddRollback(func(_ context.Context, _ ...any) error {
+			rolledBack = true
+			return nil
+		})
+
+		return fmt.Errorf("boom")
+	})
+
+	require.Error(t, err)
+	assert.True(t, rolledBack)
+}
+
+func TestRunCommitsKBeforeSQLCommit(t *testing.T) {
+	t.Parallel()
+
+	db := openFakeDBWithDriver(t, fakeDriver{failCommit: true})
+
+	stepRan := false
+
+	err := Run(t.Context(), db, func(_ *sql.Tx, k *transaction.Transaction) error {
+		k.Step(
+			func(_ context.Context, _ ...any) error {
+				stepRan = true
+				return nil
+			},
+			func(_ context.Context, _ ...any) error {
+				return nil
+			},
+		)
+
+		return nil
+	})
+
+	require.Error(t, err)
+	assert.True(t, stepRan, "k's queued operations gate the sql commit, so they must run and succeed first")
+}
+
+func TestRunRollsBackSQLWhenKOperationsFail(t *testing.T) {
+	t.Parallel()
+
+	db := openFakeDB(t)
+
+	err := Run(t.Context(), db, func(_ *sql.Tx, k *transaction.Transaction) error {
+		k.AddCommit(func(_ context.Context, _ ...any) error {
+			return fmt.Errorf("external call failed")
+		})
+
+		return nil
+	})
+
+	require.Error(t, err)
+}
+
+func TestRunRollsBackQueuedStepsWhenOneFails(t *testing.T) {
+	t.Parallel()
+
+	db := openFakeDB(t)
+
+	rolledBack := false
+
+	err := Run(t.Context(), db, func(_ *sql.Tx, k *transaction.Transaction) error {
+		k.Step(
+			func(_ context.Context, _ ...any) error {
+				return nil
+			},
+			func(_ context.Context, _ ...any) error {
+				rolledBack = true
+				return nil
+			},
+		)
+		k.Step(
+			func(_ context.Context, _ ...any) error {
+				return fmt.Errorf("second step failed")
+			},
+			func(_ context.Context, _ ...any) error {
+				return nil
+			},
+		)
+
+		return nil
+	})
+
+	require.Error(t, err)
+	assert.True(t, rolledBack)
+}
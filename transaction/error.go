@@ -16,4 +16,23 @@ var (
 
 	// ErrCannotDoRollback is returned when attempting to rollback a transaction but the rollback function has not been set.
 	ErrCannotDoRollback = &TxError{msg: "cannot do rollback. Rollback function is not set"}
+
+	// ErrPanicDuringCommit is wrapped into the error returned by doCommit when a commit
+	// function panics and WithPanicRecovery is enabled; match it with errors.Is.
+	ErrPanicDuringCommit = &TxError{msg: "panic during commit"}
+
+	// ErrAlreadyCommitted is returned when doCommit is called on a transaction that has
+	// already been committed.
+	ErrAlreadyCommitted = &TxError{msg: "transaction is already committed"}
+
+	// ErrAlreadyRolledBack is returned when doCommit or doRollback is called on a
+	// transaction that has already been rolled back.
+	ErrAlreadyRolledBack = &TxError{msg: "transaction is already rolled back"}
+
+	// ErrTransactionInProgress is returned when doCommit, doRollback, Release
+	// or RollbackTo observes
+	// a commit or RollbackTo already running on the same root, e.g. a commit
+	// or compensating rollback function re-entering the transaction it is
+	// part of.
+	ErrTransactionInProgress = &TxError{msg: "transaction commit is already in progress"}
 )
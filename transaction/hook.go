@@ -0,0 +1,80 @@
+package transaction
+
+import "context"
+
+// Committer executes a transaction's commit sequence.
+type Committer interface {
+	Commit(ctx context.Context) error
+}
+
+// committerFunc adapts a plain function to the Committer interface.
+type committerFunc func(ctx context.Context) error
+
+// Commit calls f(ctx).
+func (f committerFunc) Commit(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CommitHook wraps a Committer, allowing callers to add cross-cutting behavior
+// (logging, tracing, metrics, validation) around the whole commit sequence
+// without modifying the registered commit functions themselves.
+type CommitHook func(next Committer) Committer
+
+// Rollbacker executes a transaction's rollback sequence.
+type Rollbacker interface {
+	Rollback(ctx context.Context) error
+}
+
+// rollbackerFunc adapts a plain function to the Rollbacker interface.
+type rollbackerFunc func(ctx context.Context) error
+
+// Rollback calls f(ctx).
+func (f rollbackerFunc) Rollback(ctx context.Context) error {
+	return f(ctx)
+}
+
+// RollbackHook wraps a Rollbacker, allowing callers to add cross-cutting
+// behavior around the whole rollback sequence.
+type RollbackHook func(next Rollbacker) Rollbacker
+
+// OnCommit registers a CommitHook that wraps doCommit's executor. Hooks run in
+// FIFO registration order: the first hook registered is the outermost and
+// runs first, the innermost hook invokes the real commit function list.
+func (t *Transaction) OnCommit(hook CommitHook) *Transaction {
+	t.commitHooks = append(t.commitHooks, hook)
+
+	return t
+}
+
+// OnRollback registers a RollbackHook that wraps doRollback's executor. Hooks
+// run in FIFO registration order: the first hook registered is the outermost
+// and runs first, the innermost hook invokes the real rollback function list.
+func (t *Transaction) OnRollback(hook RollbackHook) *Transaction {
+	t.rollbackHooks = append(t.rollbackHooks, hook)
+
+	return t
+}
+
+// wrapCommit builds the Committer chain around base, applying t's commit
+// hooks so that the first registered hook is outermost.
+func (t *Transaction) wrapCommit(base Committer) Committer {
+	c := base
+
+	for i := len(t.commitHooks) - 1; i >= 0; i-- {
+		c = t.commitHooks[i](c)
+	}
+
+	return c
+}
+
+// wrapRollback builds the Rollbacker chain around base, applying t's rollback
+// hooks so that the first registered hook is outermost.
+func (t *Transaction) wrapRollback(base Rollbacker) Rollbacker {
+	r := base
+
+	for i := len(t.rollbackHooks) - 1; i >= 0; i-- {
+		r = t.rollbackHooks[i](r)
+	}
+
+	return r
+}
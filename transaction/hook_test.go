@@ -0,0 +1,79 @@
+package transaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnCommitHookOrder(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+
+	tx := NewTransaction()
+	tx.withCommit(NewCommit(func(_ context.Context, _ ...any) error {
+		order = append(order, "commit")
+		return nil
+	}))
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+
+	tx.OnCommit(func(next Committer) Committer {
+		return committerFunc(func(ctx context.Context) error {
+			order = append(order, "hook1:before")
+			err := next.Commit(ctx)
+			order = append(order, "hook1:after")
+			return err
+		})
+	})
+
+	tx.OnCommit(func(next Committer) Committer {
+		return committerFunc(func(ctx context.Context) error {
+			order = append(order, "hook2:before")
+			err := next.Commit(ctx)
+			order = append(order, "hook2:after")
+			return err
+		})
+	})
+
+	err := tx.doCommit(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"hook1:before",
+		"hook2:before",
+		"commit",
+		"hook2:after",
+		"hook1:after",
+	}, order)
+}
+
+func TestOnRollbackHookOrder(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+
+	tx := NewTransaction()
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		order = append(order, "rollback")
+		return nil
+	}))
+
+	tx.OnRollback(func(next Rollbacker) Rollbacker {
+		return rollbackerFunc(func(ctx context.Context) error {
+			order = append(order, "hook:before")
+			err := next.Rollback(ctx)
+			order = append(order, "hook:after")
+			return err
+		})
+	})
+
+	err := tx.doRollback(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"hook:before", "rollback", "hook:after"}, order)
+}
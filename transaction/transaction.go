@@ -2,50 +2,142 @@ package transaction
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// txState tracks where a Transaction is in its commit/rollback lifecycle, so
+// that doCommit/doRollback can reject re-entrant calls instead of silently
+// re-running side effects.
+type txState int32
+
+const (
+	statePending txState = iota
+	stateCommitting
+	stateRollingBackTo
+	stateCommitted
+	stateRolledBack
+	stateFailed
 )
 
 // Transaction represents a transaction that can be committed or rolled back.
 // It contains commit and rollback functions, and a flag to check if rollback is required.
 type Transaction struct {
-	commit   *commit   // function to call on commit.
-	rollback *rollback // function to call on rollback.
+	commit   *Commit   // function to call on commit.
+	rollback *Rollback // function to call on rollback.
 
 	needRollback bool // whether to check for rollback function presence during commit.
+
+	parent     *Transaction // parent transaction, set when this Transaction was created via Begin.
+	savepoints []savepoint  // stack of savepoints, indexing into commit.fns and rollback.fns.
+
+	commitHooks   []CommitHook   // hooks wrapping doCommit's executor, in FIFO order.
+	rollbackHooks []RollbackHook // hooks wrapping doRollback's executor, in FIFO order.
+
+	panicRecovery bool // whether a panicking commit function should trigger rollback instead of propagating.
+
+	mu    sync.Mutex // guards transitions of state.
+	state int32      // atomic txState; transitioned under mu.
+
+	ignoredErrors []error // errors that doCommit should tolerate and continue past, matched via errors.Is.
+
+	steps []step // saga-style steps registered via Step, each paired with its own compensating rollback.
+}
+
+// step pairs a commit function with its compensating rollback function,
+// registered via Transaction.Step to build saga-style transactions.
+type step struct {
+	commit   function
+	rollback function
+}
+
+// savepoint marks a position in the commit/rollback queues so that RollbackTo
+// can later undo only the operations queued after it, without discarding the
+// rest of the transaction.
+type savepoint struct {
+	name        string
+	commitIdx   int
+	rollbackIdx int
 }
 
 // transactionFunc is a function type that takes a context and variable arguments and returns an error.
 type transactionFunc func(ctx context.Context, args ...any) error
 
+// Func is the exported alias of transactionFunc, for callers outside the
+// package that need to name the commit/rollback function signature (e.g.
+// txkeeper).
+type Func = transactionFunc
+
 // function represents a function with its arguments that will be executed during commit or rollback.
 type function struct {
 	fn   transactionFunc
 	args []any
 }
 
-// commit contains a slice of functions to be executed during commit.
-type commit struct {
+// Function is the exported alias of function, for callers outside the
+// package that need to name the type (e.g. txkeeper).
+type Function = function
+
+// Commit contains a slice of functions to be executed during commit.
+type Commit struct {
 	fns []function
 }
 
-// rollback contains a slice of functions to be executed during rollback.
-type rollback struct {
+// Rollback contains a slice of functions to be executed during rollback.
+type Rollback struct {
 	fns []function
 }
 
-// transactionOption is a function type for configuring Transaction options.
-type transactionOption func(*Transaction)
+// Option is a function type for configuring Transaction options.
+type Option func(*Transaction)
 
 // WithNeedRollback returns an option that sets the needRollback flag to true.
-func WithNeedRollback() transactionOption {
+func WithNeedRollback() Option {
 	return func(t *Transaction) {
 		t.needRollback = true
 	}
 }
 
+// WithNoCheckRollback returns an option that sets the needRollback flag to
+// false, letting doCommit succeed even when no rollback function has been
+// registered. NewTransaction defaults needRollback to true.
+func WithNoCheckRollback() Option {
+	return func(t *Transaction) {
+		t.needRollback = false
+	}
+}
+
+// WithIgnoredErrors returns an option that makes doCommit tolerate the given
+// sentinel errors: when a commit function returns an error matching one of
+// errs (via errors.Is), doCommit accumulates it and continues executing the
+// remaining commit functions instead of aborting. The first non-ignored error
+// still aborts the chain; if every function succeeds or only returns ignored
+// errors, doCommit returns an errors.Join of the accumulated ignored errors
+// (or nil if none occurred), so callers can distinguish acceptable partial
+// failure from a real abort. Steps registered via Step are not covered: any
+// error from a step's commit function aborts the steps and runs their
+// compensation.
+func WithIgnoredErrors(errs ...error) Option {
+	return func(t *Transaction) {
+		t.ignoredErrors = append(t.ignoredErrors, errs...)
+	}
+}
+
+// WithPanicRecovery returns an option that recovers from a panicking commit
+// function, runs the registered rollback functions, and returns an error
+// wrapping ErrPanicDuringCommit with the recovered value instead of letting
+// the panic propagate.
+func WithPanicRecovery() Option {
+	return func(t *Transaction) {
+		t.panicRecovery = true
+	}
+}
+
 // NewTransaction creates a new Transaction with the given options.
 // By default, needRollback is set to true.
-func NewTransaction(opts ...transactionOption) *Transaction {
+func NewTransaction(opts ...Option) *Transaction {
 	t := &Transaction{
 		needRollback: true,
 	}
@@ -54,72 +146,485 @@ func NewTransaction(opts ...transactionOption) *Transaction {
 		opt(t)
 	}
 
-	t.commit = &commit{fns: make([]function, 0)}
-	t.rollback = &rollback{fns: make([]function, 0)}
+	t.commit = &Commit{fns: make([]function, 0)}
+	t.rollback = &Rollback{fns: make([]function, 0)}
 
 	return t
 }
 
 // NewCommit creates a new commit with a single function and its arguments.
-func NewCommit(fn transactionFunc, args ...any) *commit {
-	return &commit{
+func NewCommit(fn transactionFunc, args ...any) *Commit {
+	return &Commit{
 		fns: []function{{fn, args}},
 	}
 }
 
 // NewRollback creates a new rollback with a single function and its arguments.
-func NewRollback(fn transactionFunc, args ...any) *rollback {
-	return &rollback{
+func NewRollback(fn transactionFunc, args ...any) *Rollback {
+	return &Rollback{
 		fns: []function{{fn, args}},
 	}
 }
 
 // withCommit adds a commit function and its arguments to the transaction.
-func (t *Transaction) withCommit(commit *commit) *Transaction {
+func (t *Transaction) withCommit(commit *Commit) *Transaction {
 	t.commit = commit
 
 	return t
 }
 
+// WithCommit sets the transaction's commit function(s), see NewCommit. It is
+// the exported counterpart of withCommit, for callers outside the package
+// (e.g. the example program).
+func (t *Transaction) WithCommit(commit *Commit) *Transaction {
+	return t.withCommit(commit)
+}
+
+// WithRollback sets the transaction's rollback function(s), see NewRollback.
+// It is the exported counterpart of withRollback, for callers outside the
+// package (e.g. the example program).
+func (t *Transaction) WithRollback(rollback *Rollback) *Transaction {
+	return t.withRollback(rollback)
+}
+
+// DoCommit executes the registered commit functions (or, if Step was used,
+// the registered saga steps), subject to the transaction's hooks, panic
+// recovery and state checks. It is the exported counterpart of doCommit.
+func (t *Transaction) DoCommit(ctx context.Context) error {
+	return t.doCommit(ctx)
+}
+
+// DoRollback executes the registered rollback functions, subject to the
+// transaction's hooks and state checks. It is the exported counterpart of
+// doRollback.
+func (t *Transaction) DoRollback(ctx context.Context) error {
+	return t.doRollback(ctx)
+}
+
 // withRollback adds a rollback function and its arguments to the transaction.
-func (t *Transaction) withRollback(rollback *rollback) *Transaction {
+func (t *Transaction) withRollback(rollback *Rollback) *Transaction {
 	t.rollback = rollback
 
 	return t
 }
 
+// AddCommit appends a commit function and its arguments to the transaction's
+// existing commit queue, instead of replacing it wholesale like WithCommit
+// does. This is the exported way to grow commit.fns after construction, which
+// Begin relies on: a child transaction shares the root's commit queue, so
+// calling AddCommit on the child queues the step into the root, at the
+// position RollbackTo/Release index against.
+func (t *Transaction) AddCommit(fn transactionFunc, args ...any) *Transaction {
+	t.commit.fns = append(t.commit.fns, function{fn: fn, args: args})
+
+	return t
+}
+
+// AddRollback appends a rollback function and its arguments to the
+// transaction's existing rollback queue, instead of replacing it wholesale
+// like WithRollback does. See AddCommit.
+func (t *Transaction) AddRollback(fn transactionFunc, args ...any) *Transaction {
+	t.rollback.fns = append(t.rollback.fns, function{fn: fn, args: args})
+
+	return t
+}
+
+// Step registers a commit function paired with its own compensating rollback
+// function, building a saga-style transaction: if a later step's commit
+// function fails, doCommit automatically runs the paired rollbacks for every
+// step that already succeeded, in reverse (LIFO) order, without the caller
+// needing to call doRollback itself. Step replaces the plain commit/rollback
+// queues set by withCommit/withRollback; mixing the two is not supported.
+func (t *Transaction) Step(commitFn, rollbackFn transactionFunc, args ...any) *Transaction {
+	t.steps = append(t.steps, step{
+		commit:   function{fn: commitFn, args: args},
+		rollback: function{fn: rollbackFn, args: args},
+	})
+
+	return t
+}
+
+// doSteps executes the registered steps in order. If a step's commit function
+// fails, the paired rollback functions for every step that already succeeded
+// are run in reverse order, and the returned error joins the commit failure
+// with any rollback failures via errors.Join. If the transaction has
+// WithPanicRecovery enabled, a step's commit function panicking is treated the
+// same as it returning an error: it is converted into an error wrapping
+// ErrPanicDuringCommit and triggers the same reverse-order compensation.
+func (t *Transaction) doSteps(ctx context.Context) error {
+	succeeded := 0
+
+	for i, s := range t.steps {
+		if err := t.runStepCommit(ctx, s); err != nil {
+			errs := []error{fmt.Errorf("tx-keeper: error commit on step %d: %w", i, err)}
+
+			for j := succeeded - 1; j >= 0; j-- {
+				rb := t.steps[j].rollback
+				if rbErr := rb.fn(ctx, rb.args...); rbErr != nil {
+					errs = append(errs, fmt.Errorf("tx-keeper: error rollback on step %d: %w", j, rbErr))
+				}
+			}
+
+			return errors.Join(errs...)
+		}
+
+		succeeded++
+	}
+
+	return nil
+}
+
+// runStepCommit runs a single step's commit function. If panicRecovery is
+// enabled, a panic is recovered and converted into an error wrapping
+// ErrPanicDuringCommit so the caller's compensation loop in doSteps runs for
+// it exactly as it would for a returned error.
+func (t *Transaction) runStepCommit(ctx context.Context, s step) (err error) {
+	if t.panicRecovery {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%w: %v", ErrPanicDuringCommit, r)
+			}
+		}()
+	}
+
+	return s.commit.fn(ctx, s.commit.args...)
+}
+
 // doCommit executes all commit functions. If needRollback is true and no rollback function is set,
-// it returns an error.
-func (t *Transaction) doCommit(ctx context.Context) error {
-	if t.needRollback && t.rollback == nil {
+// it returns an error. The commit/rollback lifecycle state lives on the root
+// (see root), so a child Transaction created via Begin shares its root's
+// idempotency guarantees instead of tracking its own, separate state. The
+// root's mu only guards the state check-and-claim and the final transition,
+// not the commit functions themselves: once doCommit has claimed
+// stateCommitting it releases mu before running any user code, so a commit
+// function or hook that re-enters the same root deadlocks no longer — it
+// observes stateCommitting and gets ErrTransactionInProgress instead. A
+// second, independent caller that loses the claim race sees the same thing
+// rather than blocking until the first finishes.
+func (t *Transaction) doCommit(ctx context.Context) (err error) {
+	root := t.root()
+
+	root.mu.Lock()
+
+	switch txState(atomic.LoadInt32(&root.state)) {
+	case stateCommitting, stateRollingBackTo:
+		root.mu.Unlock()
+		return ErrTransactionInProgress
+	case stateCommitted:
+		root.mu.Unlock()
+		return ErrAlreadyCommitted
+	case stateRolledBack:
+		root.mu.Unlock()
+		return ErrAlreadyRolledBack
+	}
+
+	if len(t.steps) == 0 && t.needRollback && t.rollback == nil {
+		root.mu.Unlock()
 		return ErrCannotDoCommit
 	}
 
-	return doCommit(ctx, t.commit)
+	atomic.StoreInt32(&root.state, int32(stateCommitting))
+	root.mu.Unlock()
+
+	completed := false
+
+	// A commit that panicked, recovered or not, never sets completed and so
+	// ends in stateFailed rather than stateCommitted.
+	defer func() {
+		var ign *ignoredCommitErrors
+
+		root.mu.Lock()
+		if !completed || (err != nil && !errors.As(err, &ign)) {
+			atomic.StoreInt32(&root.state, int32(stateFailed))
+		} else {
+			atomic.StoreInt32(&root.state, int32(stateCommitted))
+		}
+		root.mu.Unlock()
+	}()
+
+	if t.panicRecovery {
+		defer func() {
+			if r := recover(); r != nil {
+				// A panic from a Step commit function is recovered in
+				// runStepCommit, and doSteps runs the paired step rollbacks
+				// for it; that panic never reaches this defer. A panic from a
+				// plain commit function, a hook, or a compensating step
+				// rollback does, and runs the plain rollback queue here.
+				if t.rollback != nil {
+					_ = doRollback(ctx, t.rollback)
+				}
+
+				err = fmt.Errorf("%w: %v", ErrPanicDuringCommit, r)
+			}
+		}()
+	}
+
+	if len(t.steps) > 0 {
+		err = t.wrapCommit(committerFunc(t.doSteps)).Commit(ctx)
+	} else {
+		committer := t.wrapCommit(committerFunc(func(ctx context.Context) error {
+			return doCommit(ctx, t.commit, t.ignoredErrors)
+		}))
+
+		err = committer.Commit(ctx)
+	}
+
+	completed = true
+
+	return err
 }
 
 // doRollback executes all rollback functions. If no rollback function is set, it returns an error.
-func (t *Transaction) doRollback(ctx context.Context) error {
+// Calling it after a successful doCommit or a prior doRollback is a no-op error
+// (ErrAlreadyCommitted / ErrAlreadyRolledBack) rather than a re-execution, so a
+// deferred DoRollback placed right after construction is always safe to call
+// even when the transaction was already committed. While a doCommit or
+// RollbackTo is running on the same root it returns ErrTransactionInProgress.
+// As with doCommit, the state lives on the root, so a child Transaction shares
+// its root's idempotency guarantees. Unlike doCommit, doRollback holds the
+// root's mu for the entire call, including the rollback functions and hooks,
+// so concurrent callers serialize instead of racing.
+func (t *Transaction) doRollback(ctx context.Context) (err error) {
+	root := t.root()
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	switch txState(atomic.LoadInt32(&root.state)) {
+	case stateCommitting, stateRollingBackTo:
+		return ErrTransactionInProgress
+	case stateCommitted:
+		return ErrAlreadyCommitted
+	case stateRolledBack:
+		return ErrAlreadyRolledBack
+	}
+
 	if t.rollback == nil {
 		return ErrCannotDoRollback
 	}
 
-	return doRollback(ctx, t.rollback)
+	defer func() {
+		if err == nil {
+			atomic.StoreInt32(&root.state, int32(stateRolledBack))
+		}
+	}()
+
+	rollbacker := t.wrapRollback(rollbackerFunc(func(ctx context.Context) error {
+		return doRollback(ctx, t.rollback)
+	}))
+
+	return rollbacker.Rollback(ctx)
 }
 
-// doCommit executes all functions in the commit slice and returns the first error encountered.
-func doCommit(ctx context.Context, commit *commit) error {
+// root returns the top-most ancestor of t, walking up through parent links.
+// Savepoints are always recorded and resolved against the root, since child
+// transactions created via Begin share the root's commit/rollback queues.
+func (t *Transaction) root() *Transaction {
+	for t.parent != nil {
+		t = t.parent
+	}
+
+	return t
+}
+
+// Begin creates a child transaction that shares t's commit and rollback queues
+// and records a named savepoint at the current position in both. The returned
+// Transaction can be used to queue further commit/rollback functions via
+// AddCommit/AddRollback, which can later be undone as a unit via
+// RollbackTo(name) without discarding operations queued before the savepoint,
+// or kept permanently via Release(name).
+func (t *Transaction) Begin(name string) *Transaction {
+	root := t.root()
+
+	root.savepoints = append(root.savepoints, savepoint{
+		name:        name,
+		commitIdx:   len(root.commit.fns),
+		rollbackIdx: len(root.rollback.fns),
+	})
+
+	return &Transaction{
+		commit:       root.commit,
+		rollback:     root.rollback,
+		needRollback: t.needRollback,
+		parent:       t,
+	}
+}
+
+// Release discards the named savepoint without rolling anything back, so it
+// can no longer be targeted by RollbackTo. Operations queued after it remain
+// part of the transaction. Release returns ErrAlreadyCommitted or
+// ErrAlreadyRolledBack once the root has already finished its lifecycle, and
+// ErrTransactionInProgress while a doCommit or RollbackTo is running, since
+// the savepoint no longer refers to anything that can still safely change.
+// Release doesn't run any user code, so it simply holds root.mu for its
+// whole check-and-mutate rather than claim-and-release like doCommit and
+// RollbackTo do.
+func (t *Transaction) Release(name string) error {
+	root := t.root()
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	switch txState(atomic.LoadInt32(&root.state)) {
+	case stateCommitting, stateRollingBackTo:
+		return ErrTransactionInProgress
+	case stateCommitted:
+		return ErrAlreadyCommitted
+	case stateRolledBack:
+		return ErrAlreadyRolledBack
+	}
+
+	for i := len(root.savepoints) - 1; i >= 0; i-- {
+		if root.savepoints[i].name == name {
+			root.savepoints = append(root.savepoints[:i], root.savepoints[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("tx-keeper: savepoint %q not found", name)
+}
+
+// RollbackTo undoes only the operations queued after the named savepoint: it
+// runs the rollback functions added since the savepoint, in reverse order,
+// then truncates both the commit and rollback queues back to the savepoint
+// and drops any later savepoints. The rest of the transaction is left intact
+// and can still be committed or rolled back as usual. RollbackTo returns
+// ErrAlreadyCommitted or ErrAlreadyRolledBack once the root has already
+// finished its lifecycle, instead of silently re-running the compensating
+// rollback for operations that a prior DoCommit/DoRollback already resolved,
+// and ErrTransactionInProgress while a doCommit or another RollbackTo is
+// running. Like doCommit, root.mu only guards the state check-and-claim and
+// the final transition: RollbackTo claims stateRollingBackTo and releases mu
+// before running the compensating rollback functions, so a compensation that
+// re-enters the same root (DoRollback/RollbackTo/DoCommit) observes
+// stateRollingBackTo and gets ErrTransactionInProgress instead of
+// deadlocking on a mutex it already holds.
+func (t *Transaction) RollbackTo(ctx context.Context, name string) error {
+	root := t.root()
+
+	root.mu.Lock()
+
+	prevState := txState(atomic.LoadInt32(&root.state))
+
+	switch prevState {
+	case stateCommitting, stateRollingBackTo:
+		root.mu.Unlock()
+		return ErrTransactionInProgress
+	case stateCommitted:
+		root.mu.Unlock()
+		return ErrAlreadyCommitted
+	case stateRolledBack:
+		root.mu.Unlock()
+		return ErrAlreadyRolledBack
+	}
+
+	idx := -1
+
+	for i := len(root.savepoints) - 1; i >= 0; i-- {
+		if root.savepoints[i].name == name {
+			idx = i
+
+			break
+		}
+	}
+
+	if idx == -1 {
+		root.mu.Unlock()
+		return fmt.Errorf("tx-keeper: savepoint %q not found", name)
+	}
+
+	sp := root.savepoints[idx]
+
+	atomic.StoreInt32(&root.state, int32(stateRollingBackTo))
+	root.mu.Unlock()
+
+	var runErr error
+
+	for i := len(root.rollback.fns) - 1; i >= sp.rollbackIdx; i-- {
+		fn := root.rollback.fns[i]
+
+		if err := fn.fn(ctx, fn.args...); err != nil {
+			runErr = fmt.Errorf("tx-keeper: error rollback to savepoint %q on func %d: %+v", name, i, err)
+			break
+		}
+	}
+
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	if runErr != nil {
+		atomic.StoreInt32(&root.state, int32(prevState))
+		return runErr
+	}
+
+	root.commit.fns = root.commit.fns[:sp.commitIdx]
+	root.rollback.fns = root.rollback.fns[:sp.rollbackIdx]
+	root.savepoints = root.savepoints[:idx]
+	atomic.StoreInt32(&root.state, int32(prevState))
+
+	return nil
+}
+
+// ignoredCommitErrors wraps the errors accumulated from ignored commit
+// failures. It marks them as tolerated: doCommit's state-transition defer
+// unwraps it via errors.As and still transitions the transaction to
+// stateCommitted, even though a non-nil error is returned to the caller so
+// they can see which errors were ignored.
+type ignoredCommitErrors struct {
+	errs []error
+}
+
+// Error joins the accumulated ignored errors into a single message.
+func (e *ignoredCommitErrors) Error() string {
+	return errors.Join(e.errs...).Error()
+}
+
+// Unwrap exposes the accumulated errors to errors.Is/errors.As.
+func (e *ignoredCommitErrors) Unwrap() []error {
+	return e.errs
+}
+
+// doCommit executes all functions in the commit slice and returns the first
+// non-ignored error encountered. Errors matching one of ignored (via
+// errors.Is) are accumulated instead of aborting the chain; if execution
+// finishes with only ignored errors, an *ignoredCommitErrors wrapping them is
+// returned so callers can tell acceptable partial failure from a real abort,
+// while doCommit still treats the commit as successful.
+func doCommit(ctx context.Context, commit *Commit, ignored []error) error {
+	var ignoredErrs []error
+
 	for i, fn := range commit.fns {
 		if err := fn.fn(ctx, fn.args...); err != nil {
+			if isIgnoredError(err, ignored) {
+				ignoredErrs = append(ignoredErrs, fmt.Errorf("tx-keeper: ignored error on func %d: %w", i, err))
+				continue
+			}
+
 			return fmt.Errorf("tx-keeper: error commit on func %d: %+v", i, err)
 		}
 	}
 
+	if len(ignoredErrs) > 0 {
+		return &ignoredCommitErrors{errs: ignoredErrs}
+	}
+
 	return nil
 }
 
+// isIgnoredError reports whether err matches any of ignored via errors.Is.
+func isIgnoredError(err error, ignored []error) bool {
+	for _, ig := range ignored {
+		if errors.Is(err, ig) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // doRollback executes all functions in the rollback slice and returns the first error encountered.
-func doRollback(ctx context.Context, rollback *rollback) error {
+func doRollback(ctx context.Context, rollback *Rollback) error {
 	for i, fn := range rollback.fns {
 		if err := fn.fn(ctx, fn.args...); err != nil {
 			return fmt.Errorf("tx-keeper: error rollback on func %d: %+v", i, err)
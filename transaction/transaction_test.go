@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -180,6 +181,499 @@ func TestDoCommit(t *testing.T) {
 	}
 }
 
+func TestDoCommitPanicRecovery(t *testing.T) {
+	t.Parallel()
+
+	rolledBack := false
+
+	tx := NewTransaction(WithPanicRecovery())
+	tx.withCommit(NewCommit(func(_ context.Context, _ ...any) error {
+		panic("boom")
+	}))
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		rolledBack = true
+		return nil
+	}))
+
+	err := tx.doCommit(t.Context())
+
+	require.Error(t, err)
+	require.ErrorIs(t, err, ErrPanicDuringCommit)
+	assert.True(t, rolledBack)
+}
+
+func TestDoCommitPanicWithoutRecovery(t *testing.T) {
+	t.Parallel()
+
+	tx := NewTransaction()
+	tx.withCommit(NewCommit(func(_ context.Context, _ ...any) error {
+		panic("boom")
+	}))
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+
+	assert.Panics(t, func() {
+		_ = tx.doCommit(t.Context())
+	})
+}
+
+func TestDoCommitIdempotent(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+
+	tx := NewTransaction()
+	tx.withCommit(NewCommit(func(_ context.Context, _ ...any) error {
+		calls++
+		return nil
+	}))
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+
+	require.NoError(t, tx.doCommit(t.Context()))
+
+	err := tx.doCommit(t.Context())
+	require.ErrorIs(t, err, ErrAlreadyCommitted)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoRollbackIdempotent(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+
+	tx := NewTransaction()
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		calls++
+		return nil
+	}))
+
+	require.NoError(t, tx.doRollback(t.Context()))
+
+	err := tx.doRollback(t.Context())
+	require.ErrorIs(t, err, ErrAlreadyRolledBack)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoCommitAfterRollback(t *testing.T) {
+	t.Parallel()
+
+	tx := NewTransaction()
+	tx.withCommit(NewCommit(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+
+	require.NoError(t, tx.doRollback(t.Context()))
+
+	err := tx.doCommit(t.Context())
+	require.ErrorIs(t, err, ErrAlreadyRolledBack)
+}
+
+func TestDoRollbackAfterCommit(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+
+	tx := NewTransaction()
+	tx.withCommit(NewCommit(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		calls++
+		return nil
+	}))
+
+	require.NoError(t, tx.doCommit(t.Context()))
+
+	err := tx.doRollback(t.Context())
+	require.ErrorIs(t, err, ErrAlreadyCommitted)
+	assert.Equal(t, 0, calls)
+}
+
+func TestDoCommitIgnoredErrors(t *testing.T) {
+	t.Parallel()
+
+	errIgnored := fmt.Errorf("ignorable error")
+
+	ran := []int{}
+
+	tx := NewTransaction(WithIgnoredErrors(errIgnored))
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+	tx.commit = &Commit{
+		fns: []function{
+			{fn: func(_ context.Context, _ ...any) error {
+				ran = append(ran, 0)
+				return errIgnored
+			}},
+			{fn: func(_ context.Context, _ ...any) error {
+				ran = append(ran, 1)
+				return nil
+			}},
+		},
+	}
+
+	err := tx.doCommit(t.Context())
+
+	require.Error(t, err)
+	require.ErrorIs(t, err, errIgnored)
+	assert.Equal(t, []int{0, 1}, ran)
+	assert.Equal(t, stateCommitted, txState(tx.state))
+}
+
+func TestDoCommitIgnoredErrorsStopsOnRealError(t *testing.T) {
+	t.Parallel()
+
+	errIgnored := fmt.Errorf("ignorable error")
+	errReal := fmt.Errorf("real error")
+
+	ran := []int{}
+
+	tx := NewTransaction(WithIgnoredErrors(errIgnored))
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+	tx.commit = &Commit{
+		fns: []function{
+			{fn: func(_ context.Context, _ ...any) error {
+				ran = append(ran, 0)
+				return errIgnored
+			}},
+			{fn: func(_ context.Context, _ ...any) error {
+				ran = append(ran, 1)
+				return errReal
+			}},
+			{fn: func(_ context.Context, _ ...any) error {
+				ran = append(ran, 2)
+				return nil
+			}},
+		},
+	}
+
+	err := tx.doCommit(t.Context())
+
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, errIgnored)
+	assert.Equal(t, []int{0, 1}, ran)
+	assert.Equal(t, stateFailed, txState(tx.state))
+}
+
+func TestStepAllSucceed(t *testing.T) {
+	t.Parallel()
+
+	var committed []int
+
+	tx := NewTransaction()
+
+	for i := 0; i < 3; i++ {
+		i := i
+		tx.Step(
+			func(_ context.Context, _ ...any) error {
+				committed = append(committed, i)
+				return nil
+			},
+			func(_ context.Context, _ ...any) error {
+				return nil
+			},
+		)
+	}
+
+	err := tx.doCommit(t.Context())
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, committed)
+}
+
+func TestStepRollsBackSucceededStepsOnFailure(t *testing.T) {
+	t.Parallel()
+
+	var rolledBack []int
+
+	tx := NewTransaction()
+
+	tx.Step(
+		func(_ context.Context, _ ...any) error {
+			return nil
+		},
+		func(_ context.Context, _ ...any) error {
+			rolledBack = append(rolledBack, 0)
+			return nil
+		},
+	)
+	tx.Step(
+		func(_ context.Context, _ ...any) error {
+			return nil
+		},
+		func(_ context.Context, _ ...any) error {
+			rolledBack = append(rolledBack, 1)
+			return nil
+		},
+	)
+	tx.Step(
+		func(_ context.Context, _ ...any) error {
+			return fmt.Errorf("step 2 failed")
+		},
+		func(_ context.Context, _ ...any) error {
+			rolledBack = append(rolledBack, 2)
+			return nil
+		},
+	)
+
+	err := tx.doCommit(t.Context())
+
+	require.Error(t, err)
+	assert.Equal(t, []int{1, 0}, rolledBack)
+}
+
+func TestStepPanicRecoveryRollsBackSucceededSteps(t *testing.T) {
+	t.Parallel()
+
+	var rolledBack []int
+
+	tx := NewTransaction(WithPanicRecovery())
+
+	tx.Step(
+		func(_ context.Context, _ ...any) error {
+			return nil
+		},
+		func(_ context.Context, _ ...any) error {
+			rolledBack = append(rolledBack, 0)
+			return nil
+		},
+	)
+	tx.Step(
+		func(_ context.Context, _ ...any) error {
+			return nil
+		},
+		func(_ context.Context, _ ...any) error {
+			rolledBack = append(rolledBack, 1)
+			return nil
+		},
+	)
+	tx.Step(
+		func(_ context.Context, _ ...any) error {
+			panic("boom")
+		},
+		func(_ context.Context, _ ...any) error {
+			rolledBack = append(rolledBack, 2)
+			return nil
+		},
+	)
+
+	err := tx.doCommit(t.Context())
+
+	require.Error(t, err)
+	require.ErrorIs(t, err, ErrPanicDuringCommit)
+	assert.Equal(t, []int{1, 0}, rolledBack)
+}
+
+func TestBeginRollbackTo(t *testing.T) {
+	t.Parallel()
+
+	tx := NewTransaction()
+
+	var order []string
+
+	addStep := func(target *Transaction, name string) {
+		target.AddCommit(func(_ context.Context, _ ...any) error {
+			order = append(order, "commit:"+name)
+			return nil
+		})
+		target.AddRollback(func(_ context.Context, _ ...any) error {
+			order = append(order, "rollback:"+name)
+			return nil
+		})
+	}
+
+	addStep(tx, "base")
+
+	child := tx.Begin("sp1")
+	addStep(child, "child")
+
+	require.Len(t, tx.commit.fns, 2)
+	require.Len(t, tx.rollback.fns, 2)
+
+	err := child.RollbackTo(t.Context(), "sp1")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"rollback:child"}, order)
+	assert.Len(t, tx.commit.fns, 1)
+	assert.Len(t, tx.rollback.fns, 1)
+	assert.Empty(t, tx.savepoints)
+}
+
+func TestRollbackToUnknownSavepoint(t *testing.T) {
+	t.Parallel()
+
+	tx := NewTransaction()
+
+	err := tx.RollbackTo(t.Context(), "missing")
+	require.Error(t, err)
+}
+
+func TestRelease(t *testing.T) {
+	t.Parallel()
+
+	tx := NewTransaction()
+
+	child := tx.Begin("sp1")
+	child.AddCommit(func(_ context.Context, _ ...any) error {
+		return nil
+	})
+
+	require.NoError(t, tx.Release("sp1"))
+	assert.Empty(t, tx.savepoints)
+
+	// operations queued under the released savepoint are kept.
+	assert.Len(t, tx.commit.fns, 1)
+
+	err := tx.RollbackTo(t.Context(), "sp1")
+	require.Error(t, err)
+}
+
+func TestRollbackToAfterCommit(t *testing.T) {
+	t.Parallel()
+
+	tx := NewTransaction()
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+
+	child := tx.Begin("sp1")
+	child.AddCommit(func(_ context.Context, _ ...any) error {
+		return nil
+	})
+
+	require.NoError(t, tx.doCommit(t.Context()))
+
+	err := child.RollbackTo(t.Context(), "sp1")
+	require.ErrorIs(t, err, ErrAlreadyCommitted)
+
+	err = tx.Release("sp1")
+	require.ErrorIs(t, err, ErrAlreadyCommitted)
+}
+
+func TestBeginChildSharesRootCommitState(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+
+	tx := NewTransaction()
+	tx.withCommit(NewCommit(func(_ context.Context, _ ...any) error {
+		calls++
+		return nil
+	}))
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+
+	child := tx.Begin("sp1")
+
+	require.NoError(t, tx.doCommit(t.Context()))
+
+	err := child.doCommit(t.Context())
+	require.ErrorIs(t, err, ErrAlreadyCommitted)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoCommitConcurrentCallersCommitOnce(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+
+	tx := NewTransaction()
+	tx.withCommit(NewCommit(func(_ context.Context, _ ...any) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}))
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_ = tx.doCommit(t.Context())
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestDoCommitReentrantReturnsInProgress(t *testing.T) {
+	t.Parallel()
+
+	tx := NewTransaction()
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+
+	var reentrantErr error
+
+	tx.withCommit(NewCommit(func(ctx context.Context, _ ...any) error {
+		reentrantErr = tx.doCommit(ctx)
+		return nil
+	}))
+
+	err := tx.doCommit(t.Context())
+	require.NoError(t, err)
+	require.ErrorIs(t, reentrantErr, ErrTransactionInProgress)
+}
+
+func TestRollbackToDuringCommitReturnsInProgress(t *testing.T) {
+	t.Parallel()
+
+	tx := NewTransaction()
+	tx.withRollback(NewRollback(func(_ context.Context, _ ...any) error {
+		return nil
+	}))
+
+	child := tx.Begin("sp1")
+
+	var reentrantErr error
+
+	tx.withCommit(NewCommit(func(ctx context.Context, _ ...any) error {
+		reentrantErr = child.RollbackTo(ctx, "sp1")
+		return nil
+	}))
+
+	err := tx.doCommit(t.Context())
+	require.NoError(t, err)
+	require.ErrorIs(t, reentrantErr, ErrTransactionInProgress)
+}
+
+func TestRollbackToReentrantReturnsInProgress(t *testing.T) {
+	t.Parallel()
+
+	tx := NewTransaction()
+
+	child := tx.Begin("sp1")
+
+	var reentrantErr error
+
+	child.AddRollback(func(ctx context.Context, _ ...any) error {
+		reentrantErr = child.RollbackTo(ctx, "sp1")
+		return nil
+	})
+
+	err := child.RollbackTo(t.Context(), "sp1")
+	require.NoError(t, err)
+	require.ErrorIs(t, reentrantErr, ErrTransactionInProgress)
+}
+
 func TestDoRollback(t *testing.T) {
 	t.Parallel()
 